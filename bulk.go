@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/SoufianeMouss/makeline-service-L8/metrics"
+	"github.com/SoufianeMouss/makeline-service-L8/transport/websocket"
+)
+
+// ErrVersionConflict is returned by VersionedOrderRepo implementations when
+// the caller's if_version doesn't match the order's current version.
+var ErrVersionConflict = errors.New("order version conflict")
+
+// VersionedOrderRepo is implemented by repos that support optimistic
+// concurrency via Order.Version, so two admins clicking "Ship" on the same
+// order at the same time can't clobber each other.
+type VersionedOrderRepo interface {
+	UpdateOrderIfVersion(order Order, ifVersion int64) error
+}
+
+// BulkOrderRepo is implemented by repos that can transition many orders in a
+// single call (a CosmosDB transactional batch, a Mongo bulk write, ...).
+type BulkOrderRepo interface {
+	BulkUpdateStatus(ids []string, from, to Status, ifVersion map[string]int64) (BulkUpdateResult, error)
+}
+
+// BulkUpdateResult reports the outcome of a bulk status transition per order
+// ID, since one bad ID in a batch of a thousand shouldn't fail the rest.
+type BulkUpdateResult struct {
+	Updated []string      `json:"updated"`
+	Skipped []BulkSkip    `json:"skipped"`
+	Failed  []BulkFailure `json:"failed"`
+}
+
+// BulkSkip records an order that was deliberately left untouched, e.g.
+// because it wasn't in FromStatus.
+type BulkSkip struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// BulkFailure records an order whose update errored, including a version
+// conflict against if_version.
+type BulkFailure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// BulkStatusRequest is the body of PUT /order/bulk.
+type BulkStatusRequest struct {
+	IDs        []string         `json:"ids"`
+	FromStatus Status           `json:"from_status"`
+	ToStatus   Status           `json:"to_status"`
+	IfVersion  map[string]int64 `json:"if_version,omitempty"`
+}
+
+// validStatusTransitions enumerates the only legal status changes:
+// Pending -> Processing -> Complete. No skips, no reversals.
+var validStatusTransitions = map[Status]Status{
+	Pending:    Processing,
+	Processing: Complete,
+}
+
+func isValidStatusTransition(from, to Status) bool {
+	next, ok := validStatusTransitions[from]
+	return ok && next == to
+}
+
+// bulkUpdateOrderStatus handles PUT /order/bulk: the admin "ship all
+// processing orders" case. The legal-transition check happens once for the
+// whole batch; the atomic per-ID update (and any version conflicts) is
+// delegated to repo.BulkUpdateStatus.
+func bulkUpdateOrderStatus(c *gin.Context) {
+	logger := loggerFromContext(c)
+
+	client, ok := c.MustGet("orderService").(*OrderService)
+	if !ok {
+		logger.Error("failed to get order service")
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	var req BulkStatusRequest
+	if err := c.BindJSON(&req); err != nil {
+		logger.Error("failed to unmarshal bulk status request", zap.Error(err))
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if len(req.IDs) == 0 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if !isValidStatusTransition(req.FromStatus, req.ToStatus) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("illegal status transition %d -> %d", req.FromStatus, req.ToStatus)})
+		return
+	}
+
+	bulkRepo, ok := client.repo.(BulkOrderRepo)
+	if !ok {
+		logger.Error("order repo does not support bulk status updates")
+		c.AbortWithStatus(http.StatusNotImplemented)
+		return
+	}
+
+	result, err := bulkRepo.BulkUpdateStatus(req.IDs, req.FromStatus, req.ToStatus, req.IfVersion)
+	if err != nil {
+		logger.Error("bulk status update failed", zap.Error(err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	if len(result.Updated) > 0 {
+		metrics.OrderStatusTransitionsTotal.
+			WithLabelValues(strconv.Itoa(int(req.FromStatus)), strconv.Itoa(int(req.ToStatus))).
+			Add(float64(len(result.Updated)))
+	}
+
+	for _, id := range result.Updated {
+		// CustomerID isn't part of BulkUpdateResult (repo.BulkUpdateStatus only
+		// deals in IDs), so look each order up before publishing. Otherwise
+		// clients subscribed to /order/ws?customerId=... never see their own
+		// order's bulk-initiated status change, since Filter.Match would be
+		// comparing against an empty CustomerID.
+		order, err := client.repo.GetOrder(id)
+		if err != nil {
+			logger.Warn("failed to look up order for bulk event publish", zap.String("order_id", id), zap.Error(err))
+			order = Order{OrderID: id}
+		}
+		order.Status = req.ToStatus
+		publishOrderEvent(c, websocket.EventOrderStatusChanged, order)
+	}
+
+	c.JSON(http.StatusMultiStatus, result)
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestIsValidStatusTransition(t *testing.T) {
+	tests := []struct {
+		name string
+		from Status
+		to   Status
+		want bool
+	}{
+		{"pending to processing", Status(0), Status(1), true},
+		{"processing to complete", Status(1), Status(2), true},
+		{"pending to complete skips processing", Status(0), Status(2), false},
+		{"complete to pending reverses", Status(2), Status(0), false},
+		{"no-op", Status(1), Status(1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isValidStatusTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("isValidStatusTransition(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
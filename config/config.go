@@ -0,0 +1,97 @@
+// Package config loads makeline-service's runtime configuration from the
+// environment (and any config file Viper finds) into a single typed struct,
+// replacing the scattered os.Getenv/os.Exit(1) calls main.go used to have.
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// Config holds everything needed to wire up the service: which order repo
+// backend to use and its connection details, the order-events broker
+// backend, and basic HTTP server settings.
+type Config struct {
+	Port       string
+	AppVersion string
+	LogFormat  string
+
+	// DBAPI selects the RepoFactory to use (see repofactory.go). Anything
+	// not registered falls back to the default backend.
+	DBAPI            string
+	DBURI            string
+	DBName           string
+	DBContainerName  string
+	DBPartitionKey   string
+	DBPartitionValue string
+	DBCollectionName string
+	DBUsername       string
+	DBPassword       string
+
+	UseWorkloadIdentityAuth bool
+
+	EventsBackend       string
+	EventsRedisAddr     string
+	EventsRedisPassword string
+	EventsRedisChannel  string
+
+	// OTLPEndpoint is where trace spans are exported. Tracing is disabled
+	// when it's empty.
+	OTLPEndpoint string
+}
+
+// Load reads the environment into a Config, applying the same CosmosDB/Mongo
+// env var fallbacks main.go relied on, and validates that the fields every
+// backend needs (the DB URI and DB name) are present. Fields only one backend
+// uses (container name, partition key, collection name, ...) are not checked
+// here.
+func Load() (*Config, error) {
+	v := viper.New()
+	v.SetEnvPrefix("")
+	v.AutomaticEnv()
+	v.SetDefault("PORT", "3001")
+
+	cfg := &Config{
+		Port:       v.GetString("PORT"),
+		AppVersion: v.GetString("APP_VERSION"),
+		LogFormat:  v.GetString("LOG_FORMAT"),
+
+		DBAPI:            v.GetString("ORDER_DB_API"),
+		DBURI:            firstNonEmpty(v.GetString("AZURE_COSMOS_RESOURCEENDPOINT"), v.GetString("ORDER_DB_URI")),
+		DBName:           v.GetString("ORDER_DB_NAME"),
+		DBContainerName:  v.GetString("ORDER_DB_CONTAINER_NAME"),
+		DBPartitionKey:   v.GetString("ORDER_DB_PARTITION_KEY"),
+		DBPartitionValue: v.GetString("ORDER_DB_PARTITION_VALUE"),
+		DBCollectionName: v.GetString("ORDER_DB_COLLECTION_NAME"),
+		DBUsername:       v.GetString("ORDER_DB_USERNAME"),
+		DBPassword:       v.GetString("ORDER_DB_PASSWORD"),
+
+		UseWorkloadIdentityAuth: v.GetBool("USE_WORKLOAD_IDENTITY_AUTH"),
+
+		EventsBackend:       v.GetString("ORDER_EVENTS_BACKEND"),
+		EventsRedisAddr:     v.GetString("ORDER_EVENTS_REDIS_ADDR"),
+		EventsRedisPassword: v.GetString("ORDER_EVENTS_REDIS_PASSWORD"),
+		EventsRedisChannel:  v.GetString("ORDER_EVENTS_REDIS_CHANNEL"),
+
+		OTLPEndpoint: v.GetString("OTEL_EXPORTER_OTLP_ENDPOINT"),
+	}
+
+	if cfg.DBURI == "" {
+		return nil, fmt.Errorf("AZURE_COSMOS_RESOURCEENDPOINT or ORDER_DB_URI must be set")
+	}
+	if cfg.DBName == "" {
+		return nil, fmt.Errorf("ORDER_DB_NAME must be set")
+	}
+
+	return cfg, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"github.com/SoufianeMouss/makeline-service-L8/config"
+	"github.com/SoufianeMouss/makeline-service-L8/logging"
+	"github.com/SoufianeMouss/makeline-service-L8/metrics"
+	"github.com/SoufianeMouss/makeline-service-L8/tracing"
+	"github.com/SoufianeMouss/makeline-service-L8/transport/websocket"
+)
+
+// Container wires up everything main() needs to run: the repo (chosen via
+// the RepoFactory registry), the order service, the events broker, the
+// logger, and the Gin router. Building it here means handlers can be unit
+// tested by constructing a Container around a fake OrderRepo, without env vars.
+type Container struct {
+	Config          *config.Config
+	Logger          *zap.Logger
+	OrderService    *OrderService
+	Broker          websocket.Broker
+	Router          *gin.Engine
+	ShutdownTracing func(context.Context) error
+}
+
+// NewContainer loads config, builds the logger, repo and broker, replays any
+// pending worker tasks, and registers all routes.
+func NewContainer() (*Container, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	logger, err := logging.New(cfg.LogFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := buildRepo(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replay any WorkerTask left pending by a crash or rolling restart before
+	// the HTTP server begins accepting traffic, so no dequeued order is lost.
+	if taskRepo, ok := repo.(WorkerTaskRepo); ok {
+		if err := replayPendingTasks(logger, taskRepo, repo); err != nil {
+			return nil, err
+		}
+	} else {
+		// Neither registered backend (cosmosdb_factory.go, mongodb_factory.go)
+		// implements WorkerTaskRepo yet, so this isn't a rare edge case: it's
+		// the default. Warn loudly at startup and keep incrementing a metric
+		// for every fetch (see fetchOrders) so "no crash-survival" shows up in
+		// dashboards/alerts instead of only a log line nobody is watching.
+		logger.Warn("order repo does not support durable worker-task persistence; WorkerTask replay and crash-survival on /order/fetch are DISABLED")
+	}
+
+	orderService := NewOrderService(repo)
+
+	// Order events broker: powers the /order/ws push feed so UIs and workers
+	// no longer need to poll GET /order?status=... In-process by default, or
+	// Redis pub/sub when ORDER_EVENTS_BACKEND=redis for horizontally-scaled
+	// deployments where every replica needs to see every event.
+	broker, err := websocket.NewBroker(cfg.EventsBackend, cfg.EventsRedisAddr, cfg.EventsRedisPassword, cfg.EventsRedisChannel, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tracing is opt-in: without an OTLP endpoint configured there's nowhere
+	// to export spans to, so skip the middleware entirely rather than spend
+	// cycles building spans no one collects.
+	shutdownTracing := func(context.Context) error { return nil }
+	if cfg.OTLPEndpoint != "" {
+		shutdownTracing, err = tracing.Init(context.Background(), cfg.OTLPEndpoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	router := gin.Default()
+	router.Use(cors.Default())
+	router.Use(RequestIDMiddleware(logger))
+	router.Use(metrics.Middleware())
+	if cfg.OTLPEndpoint != "" {
+		router.Use(tracing.Middleware())
+	}
+	router.Use(OrderMiddleware(orderService))
+	router.Use(BrokerMiddleware(broker))
+	registerRoutes(router, broker, cfg, logger)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	return &Container{
+		Config:          cfg,
+		Logger:          logger,
+		OrderService:    orderService,
+		Broker:          broker,
+		Router:          router,
+		ShutdownTracing: shutdownTracing,
+	}, nil
+}
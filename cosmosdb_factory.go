@@ -0,0 +1,16 @@
+package main
+
+import "github.com/SoufianeMouss/makeline-service-L8/config"
+
+func init() {
+	RegisterRepoFactory(AZURE_COSMOS_DB_SQL_API, newCosmosDBRepo)
+}
+
+func newCosmosDBRepo(cfg *config.Config) (OrderRepo, error) {
+	partitionKey := PartitionKey{cfg.DBPartitionKey, cfg.DBPartitionValue}
+
+	if cfg.UseWorkloadIdentityAuth {
+		return NewCosmosDBOrderRepoWithManagedIdentity(cfg.DBURI, cfg.DBName, cfg.DBContainerName, partitionKey)
+	}
+	return NewCosmosDBOrderRepo(cfg.DBURI, cfg.DBName, cfg.DBContainerName, cfg.DBPassword, partitionKey)
+}
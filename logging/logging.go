@@ -0,0 +1,26 @@
+// Package logging builds the zap.Logger used across makeline-service,
+// selecting JSON output for production or colorized console output for
+// local development via the LOG_FORMAT env var.
+package logging
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// New builds a logger for the given format ("json" or "console"). Any other
+// value, including empty, falls back to "console" so local runs stay
+// readable by default.
+func New(format string) (*zap.Logger, error) {
+	switch format {
+	case "json":
+		return zap.NewProduction()
+	default:
+		// zap.NewDevelopment's default EncoderConfig uses CapitalLevelEncoder,
+		// which doesn't colorize - build the console encoder ourselves with
+		// CapitalColorLevelEncoder so dev output actually gets color.
+		cfg := zap.NewDevelopmentConfig()
+		cfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		return cfg.Build()
+	}
+}
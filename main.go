@@ -1,46 +1,84 @@
 package main
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/SoufianeMouss/makeline-service-L8/config"
+	"github.com/SoufianeMouss/makeline-service-L8/metrics"
+	"github.com/SoufianeMouss/makeline-service-L8/tracing"
+	"github.com/SoufianeMouss/makeline-service-L8/transport/websocket"
 )
 
+// shutdownTimeout bounds how long the SIGTERM handler waits for in-flight
+// fetches to flush their WorkerTask records before the process exits anyway.
+const shutdownTimeout = 60 * time.Second
+
+// shuttingDown is flipped once a SIGTERM is received so fetchOrders stops
+// accepting new work while in-flight requests drain.
+var shuttingDown atomic.Bool
+
 // Valid database API types
 const (
 	AZURE_COSMOS_DB_SQL_API = "cosmosdbsql"
 )
 
 func main() {
-	var orderService *OrderService
-
-	// Get the database API type
-	apiType := os.Getenv("ORDER_DB_API")
-	switch apiType {
-	case "cosmosdbsql":
-		log.Printf("Using Azure CosmosDB SQL API")
-	default:
-		log.Printf("Using MongoDB API")
-	}
-
-	// Initialize the database
-	orderService, err := initDatabase(apiType)
+	container, err := NewContainer()
 	if err != nil {
-		log.Printf("Failed to initialize database: %s", err)
+		// The logger isn't built yet if NewContainer failed before reaching
+		// logging.New, so fall back to stderr here.
+		println("Failed to initialize application:", err.Error())
 		os.Exit(1)
 	}
+	defer container.Logger.Sync()
+	defer container.Broker.Close()
+	defer container.ShutdownTracing(context.Background())
+
+	logger := container.Logger
 
-	router := gin.Default()
-	router.Use(cors.Default())
-	router.Use(OrderMiddleware(orderService))
+	srv := &http.Server{Addr: ":" + container.Config.Port, Handler: container.Router}
 
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("server error", zap.Error(err))
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	<-stop
+
+	logger.Info("received shutdown signal, draining in-flight fetches", zap.Duration("timeout", shutdownTimeout))
+	shuttingDown.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Error("failed to shut down cleanly", zap.Error(err))
+	}
+}
+
+// registerRoutes wires up every HTTP route the service exposes.
+func registerRoutes(router *gin.Engine, broker websocket.Broker, cfg *config.Config, logger *zap.Logger) {
 	// For makeline/worker: fetch from queue, insert into DB, then return pending orders
 	router.GET("/order/fetch", fetchOrders)
 
+	// Live order events for the admin UI and makeline workers, filterable by
+	// ?status= and/or ?customerId=. Replaces polling GET /order?status=...
+	router.GET("/order/ws", websocket.NewHandler(broker, logger).ServeWS)
+
 	// For admin UI: list orders directly from DB (optionally filtered by status)
 	// Example: GET /order?status=1   (status=Processing)
 	//          GET /order?status=2   (status=Complete / shipped)
@@ -53,22 +91,25 @@ func main() {
 	// Update order (status, items, etc.)
 	router.PUT("/order", updateOrder)
 
+	// Bulk status transition for the admin "Ship all processing orders" case
+	router.PUT("/order/bulk", bulkUpdateOrderStatus)
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "ok",
-			"version": os.Getenv("APP_VERSION"),
+			"version": cfg.AppVersion,
 		})
 	})
-
-	router.Run(":3001")
 }
 
 // GET /order?status=0|1|2
 func listOrdersByStatus(c *gin.Context) {
+    logger := loggerFromContext(c)
+
     client, ok := c.MustGet("orderService").(*OrderService)
     if !ok {
-        log.Printf("Failed to get order service")
+        logger.Error("failed to get order service")
         c.AbortWithStatus(http.StatusInternalServerError)
         return
     }
@@ -87,7 +128,7 @@ func listOrdersByStatus(c *gin.Context) {
 
     orders, err := client.repo.GetOrdersByStatus(Status(s))
     if err != nil {
-        log.Printf("Failed to get orders by status: %s", err)
+        logger.Error("failed to get orders by status", zap.Error(err))
         c.AbortWithStatus(http.StatusInternalServerError)
         return
     }
@@ -104,36 +145,128 @@ func OrderMiddleware(orderService *OrderService) gin.HandlerFunc {
 	}
 }
 
+// BrokerMiddleware injects the order events broker into the request context
+// so handlers can publish without needing a package-level reference to it.
+func BrokerMiddleware(broker websocket.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("broker", broker)
+		c.Next()
+	}
+}
+
+// publishOrderEvent marshals order and publishes it under eventType, logging
+// (but not failing the request on) publish errors since the order is already
+// durably saved by the time this is called.
+func publishOrderEvent(c *gin.Context, eventType websocket.EventType, order Order) {
+	logger := loggerFromContext(c).With(zap.String("order_id", order.OrderID), zap.String("customer_id", order.CustomerID))
+
+	broker, ok := c.MustGet("broker").(websocket.Broker)
+	if !ok {
+		logger.Error("failed to get order events broker")
+		return
+	}
+
+	payload, err := json.Marshal(order)
+	if err != nil {
+		logger.Error("failed to marshal order for event", zap.String("event_type", string(eventType)), zap.Error(err))
+		return
+	}
+
+	event := websocket.Event{
+		Type:       eventType,
+		OrderID:    order.OrderID,
+		CustomerID: order.CustomerID,
+		Status:     int(order.Status),
+		Payload:    payload,
+		Timestamp:  time.Now(),
+	}
+
+	if err := broker.Publish(c.Request.Context(), event); err != nil {
+		logger.Error("failed to publish order event", zap.String("event_type", string(eventType)), zap.Error(err))
+	}
+}
+
 // Fetches orders from the order queue, stores them in database, then returns pending orders
 // This is primarily for the makeline/worker flow.
 func fetchOrders(c *gin.Context) {
+	logger := loggerFromContext(c)
+
+	if shuttingDown.Load() {
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+		return
+	}
+
 	client, ok := c.MustGet("orderService").(*OrderService)
 	if !ok {
-		log.Printf("Failed to get order service")
+		logger.Error("failed to get order service")
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
 
+	// WorkerTask persistence is best-effort: not every repo backend supports
+	// it yet (see NewContainer's replay step), so degrade to the old
+	// fetch-straight-to-InsertOrders behavior rather than fail the request.
+	// The counter (not just a log line) is what makes the degradation
+	// visible operationally: a crash during this path will silently drop
+	// dequeued orders until a WorkerTaskRepo-capable backend ships.
+	taskRepo, hasTaskRepo := client.repo.(WorkerTaskRepo)
+	if !hasTaskRepo {
+		metrics.WorkerTaskPersistenceUnsupportedTotal.Inc()
+	}
+
 	// Get orders from the queue
+	queueCtx, queueSpan := tracing.StartSpan(c.Request.Context(), "getOrdersFromQueue")
 	orders, err := getOrdersFromQueue()
+	queueSpan.End()
 	if err != nil {
-		log.Printf("Failed to fetch orders from queue: %s", err)
+		metrics.QueueFetchErrorsTotal.Inc()
+		logger.Error("failed to fetch orders from queue", zap.Error(err))
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
+	metrics.OrdersFetchedTotal.Add(float64(len(orders)))
+
+	// Persist each dequeued order as a WorkerTask *before* inserting it, so a
+	// crash between the queue ack and the DB insert doesn't silently drop it.
+	var tasks []WorkerTask
+	if hasTaskRepo {
+		tasks, err = persistDequeuedOrders(taskRepo, orders)
+		if err != nil {
+			logger.Error("failed to persist dequeued orders", zap.Error(err))
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+	} else {
+		logger.Warn("order repo does not support durable worker-task persistence; skipping")
+	}
 
 	// Save orders to database
+	_, insertSpan := tracing.StartSpan(queueCtx, "repo.InsertOrders")
 	err = client.repo.InsertOrders(orders)
+	insertSpan.End()
 	if err != nil {
-		log.Printf("Failed to save orders to database: %s", err)
+		logger.Error("failed to save orders to database", zap.Error(err))
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
+	metrics.OrdersInsertedTotal.Add(float64(len(orders)))
+
+	if hasTaskRepo {
+		if err := commitWorkerTasks(taskRepo, tasks); err != nil {
+			logger.Error(err.Error())
+		}
+	}
+
+	for _, order := range orders {
+		publishOrderEvent(c, websocket.EventOrderCreated, order)
+	}
 
 	// Return the orders to be processed (pending)
+	_, pendingSpan := tracing.StartSpan(queueCtx, "repo.GetPendingOrders")
 	orders, err = client.repo.GetPendingOrders()
+	pendingSpan.End()
 	if err != nil {
-		log.Printf("Failed to get pending orders from database: %s", err)
+		logger.Error("failed to get pending orders from database", zap.Error(err))
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
@@ -146,9 +279,11 @@ func fetchOrders(c *gin.Context) {
 // Example: GET /order?status=1  (Processing)
 //          GET /order?status=2  (Complete / shipped)
 func listOrders(c *gin.Context) {
+	logger := loggerFromContext(c)
+
 	client, ok := c.MustGet("orderService").(*OrderService)
 	if !ok {
-		log.Printf("Failed to get order service")
+		logger.Error("failed to get order service")
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
@@ -164,7 +299,7 @@ func listOrders(c *gin.Context) {
 		// parse status as int and cast to Status enum
 		statusInt, parseErr := strconv.Atoi(statusParam)
 		if parseErr != nil {
-			log.Printf("Invalid status query value: %s", statusParam)
+			logger.Error("invalid status query value", zap.String("status", statusParam))
 			c.AbortWithStatus(http.StatusBadRequest)
 			return
 		}
@@ -179,7 +314,7 @@ func listOrders(c *gin.Context) {
 	}
 
 	if err != nil {
-		log.Printf("Failed to get orders from database: %s", err)
+		logger.Error("failed to get orders from database", zap.Error(err))
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
@@ -189,29 +324,33 @@ func listOrders(c *gin.Context) {
 
 // Gets a single order from database by order ID
 func getOrder(c *gin.Context) {
+	logger := loggerFromContext(c)
+
 	client, ok := c.MustGet("orderService").(*OrderService)
 	if !ok {
-		log.Printf("Failed to get order service")
+		logger.Error("failed to get order service")
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
 
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		log.Printf("Failed to convert order id to int: %s", err)
+		logger.Error("failed to convert order id to int", zap.Error(err))
 		c.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
 
 	sanitizedOrderId := strconv.FormatInt(int64(id), 10)
+	annotateOrder(c, sanitizedOrderId, "")
 
 	order, err := client.repo.GetOrder(sanitizedOrderId)
 	if err != nil {
-		log.Printf("Failed to get order from database: %s", err)
+		logger.Error("failed to get order from database", zap.String("order_id", sanitizedOrderId), zap.Error(err))
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
 
+	annotateOrder(c, sanitizedOrderId, order.CustomerID)
 	c.IndentedJSON(http.StatusOK, order)
 }
 
@@ -219,9 +358,11 @@ func getOrder(c *gin.Context) {
 // For the admin "Ship" action, the UI will send PUT /order with a numeric status,
 // e.g. status = 2 (Complete = shipped).
 func updateOrder(c *gin.Context) {
+	logger := loggerFromContext(c)
+
 	client, ok := c.MustGet("orderService").(*OrderService)
 	if !ok {
-		log.Printf("Failed to get order service")
+		logger.Error("failed to get order service")
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
@@ -229,14 +370,14 @@ func updateOrder(c *gin.Context) {
 	// unmarshal the order from the request body
 	var order Order
 	if err := c.BindJSON(&order); err != nil {
-		log.Printf("Failed to unmarshal order: %s", err)
+		logger.Error("failed to unmarshal order", zap.Error(err))
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
 
 	id, err := strconv.Atoi(order.OrderID)
 	if err != nil {
-		log.Printf("Failed to convert order id to int: %s", err)
+		logger.Error("failed to convert order id to int", zap.Error(err))
 		c.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
@@ -248,79 +389,55 @@ func updateOrder(c *gin.Context) {
 		CustomerID: order.CustomerID,
 		Items:      order.Items,
 		Status:     order.Status, // e.g. Pending(0), Processing(1), Complete(2)
+		Version:    order.Version,
 	}
-
-	err = client.repo.UpdateOrder(sanitizedOrder)
-	if err != nil {
-		log.Printf("Failed to update order status: %s", err)
-		c.AbortWithStatus(http.StatusInternalServerError)
-		return
+	annotateOrder(c, sanitizedOrderId, sanitizedOrder.CustomerID)
+
+	// Look up the order's current status before overwriting it, purely so
+	// the transition metric below has a real "from" label instead of
+	// "unknown" for every single-order update.
+	fromStatus := "unknown"
+	if existing, err := client.repo.GetOrder(sanitizedOrderId); err != nil {
+		logger.Warn("failed to look up prior order status for metrics", zap.String("order_id", sanitizedOrderId), zap.Error(err))
+	} else {
+		fromStatus = strconv.Itoa(int(existing.Status))
 	}
 
-	// 202 Accepted – update is processed
-	c.Status(http.StatusAccepted)
-}
-
-// Gets an environment variable or exits if it is not set
-func getEnvVar(varName string, fallbackVarNames ...string) string {
-	value := os.Getenv(varName)
-	if value == "" {
-		for _, fallbackVarName := range fallbackVarNames {
-			value = os.Getenv(fallbackVarName)
-			if value == "" {
-				break
-			}
+	// When the caller supplies its last-known Order.Version, two admins
+	// clicking "Ship" on the same order at the same time can't clobber each
+	// other: the second write is rejected with 409 instead of silently
+	// overwriting the first. A zero Version means the caller doesn't know
+	// (or doesn't care about) the current version, so fall back to the plain
+	// unconditional update.
+	_, updateSpan := tracing.StartSpan(c.Request.Context(), "repo.UpdateOrder")
+	defer updateSpan.End()
+
+	if sanitizedOrder.Version != 0 {
+		versionedRepo, ok := client.repo.(VersionedOrderRepo)
+		if !ok {
+			logger.Error("order repo does not support optimistic concurrency")
+			c.AbortWithStatus(http.StatusNotImplemented)
+			return
 		}
-		if value == "" {
-			log.Printf("%s is not set", varName)
-			if len(fallbackVarNames) > 0 {
-				log.Printf("Tried fallback variables: %v", fallbackVarNames)
+
+		if err := versionedRepo.UpdateOrderIfVersion(sanitizedOrder, sanitizedOrder.Version); err != nil {
+			if errors.Is(err, ErrVersionConflict) {
+				c.AbortWithStatus(http.StatusConflict)
+				return
 			}
-			os.Exit(1)
+			logger.Error("failed to update order status", zap.String("order_id", sanitizedOrderId), zap.Error(err))
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
 		}
+	} else if err := client.repo.UpdateOrder(sanitizedOrder); err != nil {
+		logger.Error("failed to update order status", zap.String("order_id", sanitizedOrderId), zap.Error(err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
 	}
-	return value
-}
 
-// Initializes the database based on the API type
-func initDatabase(apiType string) (*OrderService, error) {
-	dbURI := getEnvVar("AZURE_COSMOS_RESOURCEENDPOINT", "ORDER_DB_URI")
-	dbName := getEnvVar("ORDER_DB_NAME")
-
-	switch apiType {
-	case AZURE_COSMOS_DB_SQL_API:
-		containerName := getEnvVar("ORDER_DB_CONTAINER_NAME")
-		dbPartitionKey := getEnvVar("ORDER_DB_PARTITION_KEY")
-		dbPartitionValue := getEnvVar("ORDER_DB_PARTITION_VALUE")
-
-		// check if USE_WORKLOAD_IDENTITY_AUTH is set
-		useWorkloadIdentityAuth := os.Getenv("USE_WORKLOAD_IDENTITY_AUTH")
-		if useWorkloadIdentityAuth == "" {
-			useWorkloadIdentityAuth = "false"
-		}
+	metrics.OrderStatusTransitionsTotal.WithLabelValues(fromStatus, strconv.Itoa(int(sanitizedOrder.Status))).Inc()
+	publishOrderEvent(c, websocket.EventOrderStatusChanged, sanitizedOrder)
 
-		if useWorkloadIdentityAuth == "true" {
-			cosmosRepo, err := NewCosmosDBOrderRepoWithManagedIdentity(dbURI, dbName, containerName, PartitionKey{dbPartitionKey, dbPartitionValue})
-			if err != nil {
-				return nil, err
-			}
-			return NewOrderService(cosmosRepo), nil
-		} else {
-			dbPassword := os.Getenv("ORDER_DB_PASSWORD")
-			cosmosRepo, err := NewCosmosDBOrderRepo(dbURI, dbName, containerName, dbPassword, PartitionKey{dbPartitionKey, dbPartitionValue})
-			if err != nil {
-				return nil, err
-			}
-			return NewOrderService(cosmosRepo), nil
-		}
-	default:
-		collectionName := getEnvVar("ORDER_DB_COLLECTION_NAME")
-		dbUsername := os.Getenv("ORDER_DB_USERNAME")
-		dbPassword := os.Getenv("ORDER_DB_PASSWORD")
-		mongoRepo, err := NewMongoDBOrderRepo(dbURI, dbName, collectionName, dbUsername, dbPassword)
-		if err != nil {
-			return nil, err
-		}
-		return NewOrderService(mongoRepo), nil
-	}
+	// 202 Accepted – update is processed
+	c.Status(http.StatusAccepted)
 }
@@ -0,0 +1,69 @@
+// Package metrics defines the Prometheus collectors this service exposes on
+// /metrics and the Gin middleware that records HTTP-level metrics for every
+// request.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total HTTP requests processed, labeled by route, method, and status.",
+	}, []string{"route", "method", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "http_request_duration_seconds",
+		Help: "HTTP request latency in seconds, labeled by route and method.",
+	}, []string{"route", "method"})
+
+	OrdersFetchedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "orders_fetched_total",
+		Help: "Total orders fetched from the order queue.",
+	})
+
+	OrdersInsertedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "orders_inserted_total",
+		Help: "Total orders inserted into the database.",
+	})
+
+	OrderStatusTransitionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "order_status_transitions_total",
+		Help: "Total order status transitions, labeled by from and to status.",
+	}, []string{"from", "to"})
+
+	QueueFetchErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "queue_fetch_errors_total",
+		Help: "Total errors fetching orders from the order queue.",
+	})
+
+	WorkerTaskPersistenceUnsupportedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "worker_task_persistence_unsupported_total",
+		Help: "Total /order/fetch calls served without durable WorkerTask persistence because the repo backend doesn't implement WorkerTaskRepo. Nonzero means a crash mid-fetch can silently drop dequeued orders.",
+	})
+)
+
+// Middleware records http_requests_total and http_request_duration_seconds
+// for every request. The route label uses the matched route template
+// (c.FullPath()), not the raw path, so path params don't blow up cardinality.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		HTTPRequestsTotal.WithLabelValues(route, c.Request.Method, status).Inc()
+		HTTPRequestDuration.WithLabelValues(route, c.Request.Method).Observe(time.Since(start).Seconds())
+	}
+}
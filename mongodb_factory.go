@@ -0,0 +1,11 @@
+package main
+
+import "github.com/SoufianeMouss/makeline-service-L8/config"
+
+func init() {
+	RegisterRepoFactory(defaultRepoBackend, newMongoDBRepo)
+}
+
+func newMongoDBRepo(cfg *config.Config) (OrderRepo, error) {
+	return NewMongoDBOrderRepo(cfg.DBURI, cfg.DBName, cfg.DBCollectionName, cfg.DBUsername, cfg.DBPassword)
+}
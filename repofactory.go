@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/SoufianeMouss/makeline-service-L8/config"
+)
+
+// OrderRepo is what OrderService needs from a backing store. CosmosDB,
+// MongoDB, and any future backend (Postgres, an in-memory test double, ...)
+// all satisfy it.
+type OrderRepo interface {
+	GetOrdersByStatus(status Status) ([]Order, error)
+	GetPendingOrders() ([]Order, error)
+	GetOrder(id string) (Order, error)
+	InsertOrders(orders []Order) error
+	UpdateOrder(order Order) error
+}
+
+// defaultRepoBackend is used when cfg.DBAPI doesn't match any registered
+// factory (this is also MongoDB's historical default behavior).
+const defaultRepoBackend = "mongodb"
+
+// RepoFactory builds an OrderRepo from config. Backends register their
+// factory in their own init(), so adding one no longer means editing this
+// file or main.go.
+type RepoFactory func(cfg *config.Config) (OrderRepo, error)
+
+var repoFactories = map[string]RepoFactory{}
+
+// RegisterRepoFactory makes factory available under name. Call it from an
+// init() in the backend's own file.
+func RegisterRepoFactory(name string, factory RepoFactory) {
+	repoFactories[name] = factory
+}
+
+// buildRepo looks up cfg.DBAPI in the registry, falling back to
+// defaultRepoBackend when it's unset or unrecognized.
+func buildRepo(cfg *config.Config) (OrderRepo, error) {
+	name := cfg.DBAPI
+	factory, ok := repoFactories[name]
+	if !ok {
+		factory, ok = repoFactories[defaultRepoBackend]
+		if !ok {
+			return nil, fmt.Errorf("no repo factory registered for %q", name)
+		}
+	}
+	return factory(cfg)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/SoufianeMouss/makeline-service-L8/config"
+)
+
+type fakeOrderRepo struct{}
+
+func (fakeOrderRepo) GetOrdersByStatus(status Status) ([]Order, error) { return nil, nil }
+func (fakeOrderRepo) GetPendingOrders() ([]Order, error)               { return nil, nil }
+func (fakeOrderRepo) GetOrder(id string) (Order, error)                { return Order{}, nil }
+func (fakeOrderRepo) InsertOrders(orders []Order) error                { return nil }
+func (fakeOrderRepo) UpdateOrder(order Order) error                    { return nil }
+
+func TestBuildRepoUsesRegisteredFactory(t *testing.T) {
+	RegisterRepoFactory("faketest", func(cfg *config.Config) (OrderRepo, error) {
+		return fakeOrderRepo{}, nil
+	})
+
+	repo, err := buildRepo(&config.Config{DBAPI: "faketest"})
+	if err != nil {
+		t.Fatalf("buildRepo returned error: %v", err)
+	}
+	if _, ok := repo.(fakeOrderRepo); !ok {
+		t.Fatalf("buildRepo returned %T, want fakeOrderRepo", repo)
+	}
+}
+
+func TestBuildRepoFallsBackToDefaultBackend(t *testing.T) {
+	RegisterRepoFactory(defaultRepoBackend, func(cfg *config.Config) (OrderRepo, error) {
+		return fakeOrderRepo{}, nil
+	})
+
+	repo, err := buildRepo(&config.Config{DBAPI: "unregistered-backend"})
+	if err != nil {
+		t.Fatalf("buildRepo returned error: %v", err)
+	}
+	if _, ok := repo.(fakeOrderRepo); !ok {
+		t.Fatalf("buildRepo returned %T, want fakeOrderRepo", repo)
+	}
+}
@@ -0,0 +1,69 @@
+// Package tracing configures OpenTelemetry tracing for makeline-service: a
+// root span per HTTP request, exported via OTLP to whatever collector is
+// configured through OTEL_EXPORTER_OTLP_ENDPOINT.
+package tracing
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "makeline-service"
+
+// Init points the global TracerProvider at an OTLP/gRPC exporter for
+// endpoint and returns a shutdown func to call during graceful shutdown.
+func Init(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName("makeline-service")))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// Middleware starts a root span per request, named after the matched route,
+// and makes it available to handlers via the request context.
+func Middleware() gin.HandlerFunc {
+	tracer := otel.Tracer(tracerName)
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+
+		ctx, span := tracer.Start(c.Request.Context(), route,
+			trace.WithAttributes(attribute.String("http.method", c.Request.Method)))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		span.SetAttributes(attribute.Int("http.status_code", c.Writer.Status()))
+	}
+}
+
+// StartSpan starts a child span named name from ctx. Handlers use this to
+// wrap individual queue/repo calls (getOrdersFromQueue, repo.InsertOrders,
+// repo.GetPendingOrders, repo.UpdateOrder, ...).
+func StartSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name)
+}
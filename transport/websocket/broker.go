@@ -0,0 +1,45 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Broker fans order events out to subscribers. Implementations must be safe
+// for concurrent use.
+type Broker interface {
+	// Publish delivers event to every subscription whose filter matches it.
+	Publish(ctx context.Context, event Event) error
+
+	// Subscribe registers a new listener and returns a Subscription that
+	// streams matching events until Close is called.
+	Subscribe(ctx context.Context, filter Filter) (*Subscription, error)
+
+	// Close releases any resources held by the broker (connections, goroutines, ...).
+	Close() error
+}
+
+// Subscription is a live feed of events for one subscriber.
+type Subscription struct {
+	Events <-chan Event
+	Close  func()
+}
+
+// NewBroker builds the Broker backend selected by backend (the config
+// package's Config.EventsBackend, sourced from ORDER_EVENTS_BACKEND).
+// "redis" uses a Redis pub/sub backend for horizontally-scaled deployments;
+// anything else (including empty) falls back to an in-process fan-out, which
+// is sufficient for single-instance deployments.
+func NewBroker(backend, redisAddr, redisPassword, redisChannel string, logger *zap.Logger) (Broker, error) {
+	switch backend {
+	case "redis":
+		if redisAddr == "" {
+			return nil, fmt.Errorf("ORDER_EVENTS_REDIS_ADDR is not set")
+		}
+		return NewRedisBroker(redisAddr, redisPassword, redisChannel, logger)
+	default:
+		return NewMemoryBroker(), nil
+	}
+}
@@ -0,0 +1,44 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventType identifies the kind of order event being published.
+type EventType string
+
+const (
+	EventOrderCreated       EventType = "order.created"
+	EventOrderStatusChanged EventType = "order.status_changed"
+	EventOrderUpdated       EventType = "order.updated"
+)
+
+// Event is a single order change published to subscribers. Payload carries the
+// full order as JSON so the broker doesn't need to import the order model.
+type Event struct {
+	Type       EventType       `json:"type"`
+	OrderID    string          `json:"orderId"`
+	CustomerID string          `json:"customerId,omitempty"`
+	Status     int             `json:"status"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	Timestamp  time.Time       `json:"timestamp"`
+}
+
+// Filter narrows a subscription down to the events a client cares about.
+// A zero-value Filter matches everything.
+type Filter struct {
+	Status     *int
+	CustomerID string
+}
+
+// Match reports whether the event satisfies the filter.
+func (f Filter) Match(e Event) bool {
+	if f.Status != nil && *f.Status != e.Status {
+		return false
+	}
+	if f.CustomerID != "" && f.CustomerID != e.CustomerID {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package websocket
+
+import "testing"
+
+func TestFilterMatch(t *testing.T) {
+	status1 := 1
+
+	tests := []struct {
+		name   string
+		filter Filter
+		event  Event
+		want   bool
+	}{
+		{"zero-value filter matches everything", Filter{}, Event{Status: 2, CustomerID: "cust-1"}, true},
+		{"status filter matches", Filter{Status: &status1}, Event{Status: 1}, true},
+		{"status filter rejects mismatch", Filter{Status: &status1}, Event{Status: 2}, false},
+		{"customer filter matches", Filter{CustomerID: "cust-1"}, Event{CustomerID: "cust-1"}, true},
+		{"customer filter rejects mismatch", Filter{CustomerID: "cust-1"}, Event{CustomerID: "cust-2"}, false},
+		{"status and customer must both match", Filter{Status: &status1, CustomerID: "cust-1"}, Event{Status: 1, CustomerID: "cust-2"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.event); got != tt.want {
+				t.Errorf("Filter.Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+)
+
+// subscriberBufferSize bounds how many events a slow subscriber can lag
+// behind before it starts missing events rather than blocking publishers.
+const subscriberBufferSize = 32
+
+// MemoryBroker fans events out to in-process subscribers only. It's the
+// right choice for single-instance deployments; horizontally-scaled
+// deployments should use RedisBroker instead so every instance sees every
+// event.
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[int]*memorySubscriber
+	nextID      int
+}
+
+type memorySubscriber struct {
+	filter    Filter
+	ch        chan Event
+	closeOnce sync.Once
+}
+
+// NewMemoryBroker creates an empty in-process broker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[int]*memorySubscriber)}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subscribers {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// Subscriber is lagging; drop the event rather than block publishers.
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, filter Filter) (*Subscription, error) {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	sub := &memorySubscriber{filter: filter, ch: make(chan Event, subscriberBufferSize)}
+	b.subscribers[id] = sub
+	b.mu.Unlock()
+
+	closeFunc := func() {
+		b.mu.Lock()
+		delete(b.subscribers, id)
+		b.mu.Unlock()
+		sub.closeOnce.Do(func() { close(sub.ch) })
+	}
+
+	return &Subscription{Events: sub.ch, Close: closeFunc}, nil
+}
+
+// Close shuts down every live subscription. It races harmlessly with a
+// subscriber's own Subscription.Close (e.g. ServeWS's deferred sub.Close()
+// during graceful shutdown, since http.Server.Shutdown doesn't wait for
+// hijacked websocket connections): memorySubscriber.closeOnce ensures only
+// one of them actually closes the channel.
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, sub := range b.subscribers {
+		sub.closeOnce.Do(func() { close(sub.ch) })
+		delete(b.subscribers, id)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const defaultEventsChannel = "makeline:order-events"
+
+// RedisBroker publishes order events on a Redis pub/sub channel so every
+// replica of the service sees every event, not just the instance that
+// produced it. Each subscription gets its own Redis connection and applies
+// the Filter locally after decoding.
+type RedisBroker struct {
+	client  *redis.Client
+	channel string
+	logger  *zap.Logger
+}
+
+// NewRedisBroker connects to addr and uses channel for pub/sub (defaulting to
+// defaultEventsChannel when empty).
+func NewRedisBroker(addr, password, channel string, logger *zap.Logger) (*RedisBroker, error) {
+	if channel == "" {
+		channel = defaultEventsChannel
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: password})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+	return &RedisBroker{client: client, channel: channel, logger: logger}, nil
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, filter Filter) (*Subscription, error) {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return nil, err
+	}
+
+	out := make(chan Event, subscriberBufferSize)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(out)
+		msgs := pubsub.Channel()
+		for {
+			select {
+			case <-done:
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				var event Event
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					b.logger.Error("failed to decode redis event", zap.Error(err))
+					continue
+				}
+				if !filter.Match(event) {
+					continue
+				}
+				select {
+				case out <- event:
+				default:
+					// Subscriber is lagging; drop the event rather than block the reader goroutine.
+				}
+			}
+		}
+	}()
+
+	closeFunc := func() {
+		close(done)
+		pubsub.Close()
+	}
+
+	return &Subscription{Events: out, Close: closeFunc}, nil
+}
+
+func (b *RedisBroker) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TaskState tracks a WorkerTask's progress through the dequeue -> insert
+// pipeline so a replay on restart knows what's still outstanding.
+type TaskState string
+
+const (
+	TaskStatePending   TaskState = "pending"
+	TaskStateCommitted TaskState = "committed"
+)
+
+// WorkerTask durably records an order dequeued from the order queue but not
+// yet committed to the database, so it can be replayed if the process
+// crashes or restarts between the dequeue and the DB insert.
+type WorkerTask struct {
+	ID         string    `json:"id"`
+	Payload    Order     `json:"payload"`
+	DequeuedAt time.Time `json:"dequeued_at"`
+	Attempts   int       `json:"attempts"`
+	State      TaskState `json:"state"`
+}
+
+// WorkerTaskRepo persists in-flight WorkerTask records. An order repo opts in
+// by implementing it on the same value as OrderRepo; NewContainer and
+// fetchOrders detect it with a type assertion on the repo. The CosmosDB and
+// MongoDB backends registered in cosmosdb_factory.go and mongodb_factory.go
+// do not implement it, so with them dequeued orders are not persisted before
+// the insert and nothing is replayed at startup.
+type WorkerTaskRepo interface {
+	SaveWorkerTasks(tasks []WorkerTask) error
+	DeleteWorkerTasks(ids []string) error
+	ListWorkerTasksByDequeuedAt() ([]WorkerTask, error)
+}
+
+// persistDequeuedOrders writes a WorkerTask for each order *before* it is
+// handed to InsertOrders, so a crash between the queue ack and the DB insert
+// doesn't silently drop the order.
+func persistDequeuedOrders(repo WorkerTaskRepo, orders []Order) ([]WorkerTask, error) {
+	now := time.Now()
+	tasks := make([]WorkerTask, len(orders))
+	for i, order := range orders {
+		tasks[i] = WorkerTask{
+			ID:         order.OrderID,
+			Payload:    order,
+			DequeuedAt: now,
+			Attempts:   1,
+			State:      TaskStatePending,
+		}
+	}
+
+	if err := repo.SaveWorkerTasks(tasks); err != nil {
+		return nil, fmt.Errorf("failed to persist dequeued orders: %w", err)
+	}
+	return tasks, nil
+}
+
+// commitWorkerTasks deletes the WorkerTask records for orders that have been
+// acknowledged by the database insert.
+func commitWorkerTasks(repo WorkerTaskRepo, tasks []WorkerTask) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+
+	if err := repo.DeleteWorkerTasks(ids); err != nil {
+		return fmt.Errorf("failed to clear committed worker tasks: %w", err)
+	}
+	return nil
+}
+
+// replayPendingTasks scans the WorkerTask store in ascending dequeued_at
+// order and replays any task still pending back through InsertOrders. It
+// runs at startup, before the HTTP server begins accepting traffic, so an
+// in-flight order from a prior crash is never silently dropped.
+func replayPendingTasks(logger *zap.Logger, taskRepo WorkerTaskRepo, orderRepo OrderRepo) error {
+	tasks, err := taskRepo.ListWorkerTasksByDequeuedAt()
+	if err != nil {
+		return fmt.Errorf("failed to list worker tasks for replay: %w", err)
+	}
+
+	pending := make([]WorkerTask, 0, len(tasks))
+	for _, task := range tasks {
+		if task.State == TaskStatePending {
+			pending = append(pending, task)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].DequeuedAt.Before(pending[j].DequeuedAt) })
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	logger.Info("replaying worker tasks left over from a previous run", zap.Int("count", len(pending)))
+
+	orders := make([]Order, len(pending))
+	for i, task := range pending {
+		orders[i] = task.Payload
+	}
+
+	if err := orderRepo.InsertOrders(orders); err != nil {
+		return fmt.Errorf("failed to replay pending worker tasks: %w", err)
+	}
+
+	return commitWorkerTasks(taskRepo, pending)
+}